@@ -0,0 +1,112 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import "fmt"
+
+// Config holds the settings for a single configured OAuth 2.0 identity
+// provider. Driver selects which ProviderDriver handles the token exchange;
+// the remaining fields are interpreted per driver, as documented on each
+// field below.
+type Config struct {
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty"`
+
+	ClientID     string   `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+
+	// ServerName is the host of the identity provider's own deployment, e.g.
+	// the GitLab or OpenShift cluster host. Drivers with a per-install API
+	// (gitlab, openshift) derive UserInfoURL from it when UserInfoURL is
+	// left unset.
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	// UserInfoURL is the user-info endpoint to call. Required, directly or
+	// via ServerName discovery, for drivers with no fixed, single-tenant
+	// endpoint (gitlab, openshift, casdoor); ignored by drivers with a fixed
+	// endpoint (github, facebook, linkedin, discord, bitbucket).
+	UserInfoURL string `json:"user_info_url,omitempty" yaml:"user_info_url,omitempty"`
+
+	UserOrgFilters   []string `json:"user_org_filters,omitempty" yaml:"user_org_filters,omitempty"`
+	UserGroupFilters []string `json:"user_group_filters,omitempty" yaml:"user_group_filters,omitempty"`
+
+	// DiscordBotToken authenticates the discord driver's bot-token guild
+	// lookups (role names, guild membership) used by FetchGroups. It is
+	// unused by every other driver.
+	DiscordBotToken string `json:"discord_bot_token,omitempty" yaml:"discord_bot_token,omitempty"`
+
+	// PolicyMap translates the team/organization/guild groups this identity
+	// provider emits into authcrunch roles. See PolicyMap for its schema.
+	PolicyMap *PolicyMap `json:"policy_map,omitempty" yaml:"policy_map,omitempty"`
+}
+
+// supportedDrivers enumerates the accepted values of Driver.
+var supportedDrivers = map[string]bool{
+	"github":    true,
+	"gitlab":    true,
+	"facebook":  true,
+	"linkedin":  true,
+	"discord":   true,
+	"openshift": true,
+	"bitbucket": true,
+	"casdoor":   true,
+}
+
+// Validate checks the configuration for errors, discovering driver-specific
+// fields, e.g. UserInfoURL, from ServerName where the driver supports it.
+func (cfg *Config) Validate() error {
+	if cfg.Name == "" {
+		return fmt.Errorf("identity provider name is not set")
+	}
+	if cfg.Driver == "" {
+		return fmt.Errorf("identity provider %q: driver is not set", cfg.Name)
+	}
+	if !supportedDrivers[cfg.Driver] {
+		return fmt.Errorf("identity provider %q: driver %q is unsupported", cfg.Name, cfg.Driver)
+	}
+
+	switch cfg.Driver {
+	case "gitlab":
+		if cfg.UserInfoURL == "" {
+			if cfg.ServerName == "" {
+				return fmt.Errorf("identity provider %q: gitlab driver requires server_name or user_info_url", cfg.Name)
+			}
+			cfg.UserInfoURL = fmt.Sprintf("https://%s/oauth/userinfo", cfg.ServerName)
+		}
+	case "openshift":
+		if cfg.UserInfoURL == "" {
+			if cfg.ServerName == "" {
+				return fmt.Errorf("identity provider %q: openshift driver requires server_name or user_info_url", cfg.Name)
+			}
+			cfg.UserInfoURL = fmt.Sprintf("https://%s/apis/user.openshift.io/v1/users/~", cfg.ServerName)
+		}
+	case "casdoor":
+		// Casdoor is self-hosted per deployment and has no default host, so,
+		// unlike gitlab/openshift, UserInfoURL cannot be discovered from
+		// ServerName and must be configured explicitly.
+		if cfg.UserInfoURL == "" {
+			return fmt.Errorf("identity provider %q: casdoor driver requires user_info_url", cfg.Name)
+		}
+	case "bitbucket":
+		// Bitbucket Cloud's API is fixed (api.bitbucket.org); no endpoint
+		// configuration is required or consulted.
+	}
+
+	if err := cfg.PolicyMap.Validate(); err != nil {
+		return fmt.Errorf("identity provider %q: %v", cfg.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ProviderDriver is the set of operations fetchClaims needs from a specific
+// OAuth 2.0 identity provider. Implementing this interface and registering
+// it with Register is all that is required to add support for a new
+// provider; no changes to fetchClaims itself are needed.
+type ProviderDriver interface {
+	// AuthHeader returns the HTTP header name and value used to
+	// authenticate the UserInfoRequest with the given access token. A
+	// driver that authenticates the request another way, e.g. via a query
+	// string parameter, returns two empty strings.
+	AuthHeader(token string) (string, string)
+	// UserInfoRequest builds the HTTP request used to fetch the user's
+	// profile from the identity provider.
+	UserInfoRequest(b *IdentityProvider, token string) (*http.Request, error)
+	// ParseUserInfo validates and parses the user-info response body,
+	// returning the raw claims extracted from it and any group strings
+	// the response carries directly.
+	ParseUserInfo(b *IdentityProvider, body []byte) (map[string]interface{}, []string, error)
+	// FetchGroups performs any additional provider-specific calls needed
+	// to enumerate the user's teams, organizations, workspaces, or guilds.
+	// A driver that has nothing left to fetch, because ParseUserInfo
+	// already returned every group, returns nil, nil. ctx governs the
+	// lifetime of any HTTP calls the driver makes.
+	FetchGroups(ctx context.Context, b *IdentityProvider, token string) ([]string, error)
+}
+
+// driverRegistry holds the factories registered via Register, keyed by the
+// provider name used in the `driver` configuration field.
+var driverRegistry = make(map[string]func() ProviderDriver)
+
+// Register adds a ProviderDriver factory to the registry under name. It is
+// meant to be called from the init() function of a file implementing a
+// single provider, e.g. driver_github.go.
+func Register(name string, factory func() ProviderDriver) {
+	driverRegistry[name] = factory
+}
+
+// newProviderDriver looks up the ProviderDriver registered under name.
+func newProviderDriver(name string) (ProviderDriver, error) {
+	factory, exists := driverRegistry[name]
+	if !exists {
+		return nil, fmt.Errorf("provider %s is unsupported for fetching claims", name)
+	}
+	return factory(), nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("bitbucket", func() ProviderDriver { return &bitbucketDriver{} })
+}
+
+type bitbucketDriver struct{}
+
+func (d *bitbucketDriver) AuthHeader(token string) (string, string) {
+	return "Authorization", "Bearer " + token
+}
+
+func (d *bitbucketDriver) UserInfoRequest(b *IdentityProvider, token string) (*http.Request, error) {
+	return http.NewRequest("GET", "https://api.bitbucket.org/2.0/user", nil)
+}
+
+func (d *bitbucketDriver) ParseUserInfo(b *IdentityProvider, body []byte) (map[string]interface{}, []string, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, nil, err
+	}
+
+	if _, exists := data["username"]; !exists {
+		return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, username field not found")
+	}
+
+	m := make(map[string]interface{})
+	if v, exists := data["username"]; exists {
+		switch username := v.(type) {
+		case string:
+			m["sub"] = "bitbucket.org/" + username
+		}
+	}
+	if v, exists := data["display_name"]; exists {
+		switch displayName := v.(type) {
+		case string:
+			m["name"] = displayName
+		}
+	}
+	if links, exists := data["links"]; exists {
+		switch links := links.(type) {
+		case map[string]interface{}:
+			if avatar, exists := links["avatar"]; exists {
+				switch avatar := avatar.(type) {
+				case map[string]interface{}:
+					if href, exists := avatar["href"]; exists {
+						switch href := href.(type) {
+						case string:
+							m["picture"] = href
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return m, nil, nil
+}
+
+func (d *bitbucketDriver) FetchGroups(ctx context.Context, b *IdentityProvider, token string) ([]string, error) {
+	if len(b.userOrgFilters) == 0 {
+		return nil, nil
+	}
+
+	reqURL := "https://api.bitbucket.org/2.0/workspaces?role=member"
+	cli, err := b.httpClientFor()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := b.readLimitedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	b.logger.Debug("Received user workspace information", zap.String("url", reqURL), zap.Any("body", respBody))
+
+	var workspaces struct {
+		Values []map[string]interface{} `json:"values"`
+	}
+	if err := json.Unmarshal(respBody, &workspaces); err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	for _, workspace := range workspaces.Values {
+		v, exists := workspace["slug"]
+		if !exists {
+			continue
+		}
+		workspaceSlug, ok := v.(string)
+		if !ok {
+			continue
+		}
+		included := false
+		for _, rp := range b.userOrgFilters {
+			if rp.MatchString(workspaceSlug) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			continue
+		}
+		groups = append(groups, fmt.Sprintf("bitbucket.org/%s/members", workspaceSlug))
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"go.uber.org/zap"
+)
+
+func TestFetchClaimsBitbucket(t *testing.T) {
+	testcases := []struct {
+		name           string
+		respBody       string
+		workspaceResp  string
+		userOrgFilters []string
+		want           map[string]interface{}
+		shouldErr      bool
+		err            error
+	}{
+		{
+			name: "valid bitbucket user with workspaces",
+			respBody: `{
+				"username": "jsmith",
+				"display_name": "John Smith",
+				"links": {"avatar": {"href": "https://bitbucket.org/account/jsmith/avatar.png"}}
+			}`,
+			workspaceResp:  `{"values": [{"slug": "acme"}]}`,
+			userOrgFilters: []string{"acme"},
+			want: map[string]interface{}{
+				"sub":     "bitbucket.org/jsmith",
+				"name":    "John Smith",
+				"picture": "https://bitbucket.org/account/jsmith/avatar.png",
+				"groups":  []string{"bitbucket.org/acme/members"},
+			},
+		},
+		{
+			name:      "missing username field",
+			respBody:  `{"display_name": "John Smith"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, username field not found"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/2.0/user", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tc.respBody))
+			})
+			mux.HandleFunc("/2.0/workspaces", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tc.workspaceResp))
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			srvURL, err := url.Parse(srv.URL)
+			if err != nil {
+				t.Fatalf("failed parsing test server URL: %v", err)
+			}
+
+			var userOrgFilters []*regexp.Regexp
+			for _, p := range tc.userOrgFilters {
+				userOrgFilters = append(userOrgFilters, regexp.MustCompile(p))
+			}
+
+			b := &IdentityProvider{
+				logger:         zap.NewNop(),
+				httpClient:     &http.Client{Transport: hostRedirectTransport{base: srvURL}},
+				userOrgFilters: userOrgFilters,
+			}
+			b.config = &Config{Driver: "bitbucket", Name: "bitbucket_test"}
+
+			got, err := b.fetchClaims(context.Background(), map[string]interface{}{"access_token": "test-token"})
+			if tests.EvalErrWithLog(t, err, "fetchClaims", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			if tc.want != nil {
+				tc.want["origin"] = "https://api.bitbucket.org/2.0/user"
+			}
+			tests.EvalObjectsWithLog(t, "claims", tc.want, got, msgs)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"go.uber.org/zap"
+)
+
+func TestFetchClaimsCasdoor(t *testing.T) {
+	testcases := []struct {
+		name             string
+		respBody         string
+		userGroupFilters []string
+		want             map[string]interface{}
+		shouldErr        bool
+		err              error
+	}{
+		{
+			name: "valid casdoor userinfo",
+			respBody: `{
+				"sub": "jsmith",
+				"name": "John Smith",
+				"picture": "https://casdoor.example.com/avatar/jsmith.png",
+				"email": "jsmith@example.com",
+				"groups": ["engineering"]
+			}`,
+			userGroupFilters: []string{"engineering"},
+			want: map[string]interface{}{
+				"sub":     "jsmith",
+				"name":    "John Smith",
+				"picture": "https://casdoor.example.com/avatar/jsmith.png",
+				"email":   "jsmith@example.com",
+				"groups":  []string{"engineering"},
+			},
+		},
+		{
+			name:      "missing sub field",
+			respBody:  `{"name": "John Smith"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, sub field not found"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tc.respBody))
+			}))
+			defer srv.Close()
+
+			var userGroupFilters []*regexp.Regexp
+			for _, p := range tc.userGroupFilters {
+				userGroupFilters = append(userGroupFilters, regexp.MustCompile(p))
+			}
+
+			b := &IdentityProvider{
+				logger:           zap.NewNop(),
+				userInfoURL:      srv.URL,
+				userGroupFilters: userGroupFilters,
+			}
+			b.config = &Config{Driver: "casdoor", Name: "casdoor_test"}
+
+			got, err := b.fetchClaims(context.Background(), map[string]interface{}{"access_token": "test-token"})
+			if tests.EvalErrWithLog(t, err, "fetchClaims", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			if tc.want != nil {
+				tc.want["origin"] = srv.URL
+			}
+			tests.EvalObjectsWithLog(t, "claims", tc.want, got, msgs)
+		})
+	}
+}
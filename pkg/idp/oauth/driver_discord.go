@@ -0,0 +1,370 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("discord", func() ProviderDriver { return &discordDriver{} })
+}
+
+type discordMember struct {
+	Roles []string `json:"roles"`
+}
+
+type discordRole struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// discordDriver keeps the authenticated user's Discord ID around between
+// ParseUserInfo and FetchGroups, since both run against the same driver
+// instance within a single fetchClaims call.
+type discordDriver struct {
+	userID string
+}
+
+func (d *discordDriver) AuthHeader(token string) (string, string) {
+	return "Authorization", "Bearer " + token
+}
+
+func (d *discordDriver) UserInfoRequest(b *IdentityProvider, token string) (*http.Request, error) {
+	return http.NewRequest("GET", "https://discord.com/api/v10/users/@me", nil)
+}
+
+func (d *discordDriver) ParseUserInfo(b *IdentityProvider, body []byte) (map[string]interface{}, []string, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, nil, err
+	}
+
+	if _, exists := data["id"]; !exists {
+		return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, id field not found")
+	}
+
+	d.userID = data["id"].(string)
+
+	m := make(map[string]interface{})
+	m["sub"] = "discord.com/" + data["id"].(string)
+	m["name"] = data["username"]
+	m["picture"] = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png", data["id"], data["avatar"])
+	if v, exists := data["email"]; exists {
+		m["email"] = v
+	}
+
+	b.logger.Debug(
+		"Extracted UserInfo endpoint data",
+		zap.String("identity_provider_name", b.config.Name),
+		zap.Any("inputted", data),
+		zap.Any("extracted", m),
+	)
+
+	return m, nil, nil
+}
+
+func (d *discordDriver) FetchGroups(ctx context.Context, b *IdentityProvider, token string) ([]string, error) {
+	if !b.ScopeExists("guilds") {
+		return nil, nil
+	}
+
+	var req *http.Request
+	reqURL := "https://discord.com/api/v10/users/@me/guilds"
+
+	cli, err := b.httpClientFor()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := b.readLimitedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	b.logger.Debug(
+		"Received user guild infomation",
+		zap.String("url", reqURL),
+		zap.Any("body", respBody),
+	)
+
+	guilds := []map[string]interface{}{}
+	if err := json.Unmarshal(respBody, &guilds); err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	for _, guild := range guilds {
+		guildID := guild["id"].(string)
+		included := false
+		for _, rp := range b.userGroupFilters {
+			if rp.MatchString(guildID) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			continue
+		}
+
+		b.logger.Debug(
+			"Checking Guild Permissions",
+			zap.String("guildName", guild["name"].(string)),
+		)
+
+		if _, exists := guild["permissions"]; exists {
+			// Parses to int64 for 32-bit system support
+			perm, err := strconv.ParseInt(guild["permissions"].(string), 10, 64)
+			if err != nil {
+				b.logger.Debug(
+					"Error converting Guild permissions to integer",
+					zap.Any("error", err),
+				)
+			} else if (perm & 0x08) == 0x08 { // Check for admin privileges
+				groups = append(groups, fmt.Sprintf("discord.com/%s/admins", guildID))
+			}
+		}
+
+		groups = append(groups, fmt.Sprintf("discord.com/%s/members", guildID))
+
+		roleIDs := make(map[string]bool)
+		var orderedRoleIDs []string
+		addRoleID := func(roleID string) {
+			if roleIDs[roleID] {
+				return
+			}
+			roleIDs[roleID] = true
+			orderedRoleIDs = append(orderedRoleIDs, roleID)
+		}
+
+		scopeRoleIDs, err := b.fetchDiscordGuildRolesByScope(ctx, guildID, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, roleID := range scopeRoleIDs {
+			addRoleID(roleID)
+		}
+
+		if b.config.DiscordBotToken != "" && d.userID != "" {
+			botRoleIDs, err := b.fetchDiscordGuildMemberRolesByBotToken(ctx, guildID, d.userID)
+			if err != nil {
+				b.logger.Error(
+					"Failed fetching guild member roles via bot token",
+					zap.String("identity_provider_name", b.config.Name),
+					zap.String("guild_id", guildID),
+					zap.Error(err),
+				)
+			} else {
+				for _, roleID := range botRoleIDs {
+					addRoleID(roleID)
+				}
+			}
+		}
+
+		for _, roleID := range orderedRoleIDs {
+			groups = append(groups, fmt.Sprintf("discord.com/%s/role/%s", guildID, roleID))
+		}
+
+		if len(orderedRoleIDs) > 0 && b.config.DiscordBotToken != "" {
+			roleNames, err := b.fetchDiscordGuildRoleNames(ctx, guildID)
+			if err != nil {
+				b.logger.Error(
+					"Failed fetching guild role metadata",
+					zap.String("identity_provider_name", b.config.Name),
+					zap.String("guild_id", guildID),
+					zap.Error(err),
+				)
+			} else {
+				for _, roleID := range orderedRoleIDs {
+					if name, exists := roleNames[roleID]; exists {
+						groups = append(groups, fmt.Sprintf("discord.com/%s/role-name/%s", guildID, name))
+					}
+				}
+			}
+		}
+
+		b.logger.Debug(
+			"Parsed additional discord user data",
+			zap.String("url", reqURL),
+			zap.Any("groups", groups),
+		)
+	}
+
+	return groups, nil
+}
+
+// fetchDiscordGuildRolesByScope enumerates the user's role IDs within
+// guildID using the guilds.members.read scope on the user's own token.
+func (b *IdentityProvider) fetchDiscordGuildRolesByScope(ctx context.Context, guildID, userToken string) ([]string, error) {
+	if !b.ScopeExists("guilds.members.read") {
+		return nil, nil
+	}
+
+	cli, err := b.httpClientFor()
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := fmt.Sprintf("https://discord.com/api/v10/users/@me/guilds/%s/member", guildID)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+userToken)
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := b.readLimitedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberData discordMember
+	if err := json.Unmarshal(respBody, &memberData); err != nil {
+		b.logger.Debug(
+			"Guild Roles request failed",
+			zap.Any("response", respBody),
+			zap.Any("error", err),
+		)
+		return nil, err
+	}
+
+	return memberData.Roles, nil
+}
+
+// fetchDiscordGuildMemberRolesByBotToken enumerates userID's role IDs within
+// guildID using b.config.DiscordBotToken, for OAuth apps whose user token
+// was not granted the guilds.members.read scope.
+func (b *IdentityProvider) fetchDiscordGuildMemberRolesByBotToken(ctx context.Context, guildID, userID string) ([]string, error) {
+	cli, err := b.httpClientFor()
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := fmt.Sprintf("https://discord.com/api/v10/guilds/%s/members/%s", guildID, userID)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Authorization", "Bot "+b.config.DiscordBotToken)
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := b.readLimitedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberData discordMember
+	if err := json.Unmarshal(respBody, &memberData); err != nil {
+		return nil, err
+	}
+
+	return memberData.Roles, nil
+}
+
+// discordRoleCacheTTL bounds how long fetchDiscordGuildRoleNames trusts a
+// guild's cached role-ID-to-name mapping before refetching it.
+const discordRoleCacheTTL = 10 * time.Minute
+
+type discordRoleCacheEntry struct {
+	names     map[string]string
+	expiresAt time.Time
+}
+
+var (
+	discordRoleCacheMu sync.Mutex
+	discordRoleCache   = make(map[string]discordRoleCacheEntry)
+)
+
+// fetchDiscordGuildRoleNames returns a role-ID-to-name mapping for guildID,
+// fetched via b.config.DiscordBotToken and cached in-memory for
+// discordRoleCacheTTL so that resolving role names for many users in the
+// same guild does not mean one Discord API call per login.
+func (b *IdentityProvider) fetchDiscordGuildRoleNames(ctx context.Context, guildID string) (map[string]string, error) {
+	discordRoleCacheMu.Lock()
+	entry, exists := discordRoleCache[guildID]
+	discordRoleCacheMu.Unlock()
+	if exists && time.Now().Before(entry.expiresAt) {
+		return entry.names, nil
+	}
+
+	cli, err := b.httpClientFor()
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := fmt.Sprintf("https://discord.com/api/v10/guilds/%s/roles", guildID)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Authorization", "Bot "+b.config.DiscordBotToken)
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := b.readLimitedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []discordRole
+	if err := json.Unmarshal(respBody, &roles); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(roles))
+	for _, role := range roles {
+		names[role.ID] = role.Name
+	}
+
+	discordRoleCacheMu.Lock()
+	discordRoleCache[guildID] = discordRoleCacheEntry{names: names, expiresAt: time.Now().Add(discordRoleCacheTTL)}
+	discordRoleCacheMu.Unlock()
+
+	return names, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFetchDiscordGuildRoleNamesCachesAcrossCalls(t *testing.T) {
+	var roleRequests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v10/guilds/123/roles", func(w http.ResponseWriter, r *http.Request) {
+		roleRequests++
+		if got := r.Header.Get("Authorization"); got != "Bot test-bot-token" {
+			t.Errorf("unexpected Authorization header: %s", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id": "1", "name": "admins"}, {"id": "2", "name": "members"}]`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	srvURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("failed parsing test server URL: %v", err)
+	}
+
+	b := &IdentityProvider{
+		logger:     zap.NewNop(),
+		httpClient: &http.Client{Transport: hostRedirectTransport{base: srvURL}},
+	}
+	b.config = &Config{Driver: "discord", Name: "discord_test", DiscordBotToken: "test-bot-token"}
+
+	for i := 0; i < 2; i++ {
+		names, err := b.fetchDiscordGuildRoleNames(context.Background(), "123")
+		if err != nil {
+			t.Fatalf("fetchDiscordGuildRoleNames failed: %v", err)
+		}
+		if names["1"] != "admins" || names["2"] != "members" {
+			t.Fatalf("unexpected role names: %v", names)
+		}
+	}
+
+	if roleRequests != 1 {
+		t.Fatalf("expected the role metadata request to be cached, got %d requests", roleRequests)
+	}
+}
+
+func TestFetchGroupsDedupesBotAndScopeRoles(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v10/users/@me/guilds", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id": "123", "name": "Acme Guild", "permissions": "0"}]`)
+	})
+	mux.HandleFunc("/api/v10/users/@me/guilds/123/member", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"roles": ["1"]}`)
+	})
+	mux.HandleFunc("/api/v10/guilds/123/members/42", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bot test-bot-token" {
+			t.Errorf("unexpected Authorization header: %s", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"roles": ["1", "2"]}`)
+	})
+	mux.HandleFunc("/api/v10/guilds/123/roles", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"id": "1", "name": "admins"}, {"id": "2", "name": "members"}]`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	srvURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("failed parsing test server URL: %v", err)
+	}
+
+	b := &IdentityProvider{
+		logger:           zap.NewNop(),
+		httpClient:       &http.Client{Transport: hostRedirectTransport{base: srvURL}},
+		scopeMap:         map[string]bool{"guilds": true, "guilds.members.read": true},
+		userGroupFilters: []*regexp.Regexp{regexp.MustCompile("123")},
+	}
+	b.config = &Config{Driver: "discord", Name: "discord_test", DiscordBotToken: "test-bot-token"}
+
+	d := &discordDriver{userID: "42"}
+	groups, err := d.FetchGroups(context.Background(), b, "user-token")
+	if err != nil {
+		t.Fatalf("FetchGroups failed: %v", err)
+	}
+
+	want := map[string]bool{
+		"discord.com/123/members":           true,
+		"discord.com/123/role/1":            true,
+		"discord.com/123/role/2":            true,
+		"discord.com/123/role-name/admins":  true,
+		"discord.com/123/role-name/members": true,
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	for _, g := range groups {
+		if !want[g] {
+			t.Fatalf("unexpected group %q in %v", g, groups)
+		}
+	}
+}
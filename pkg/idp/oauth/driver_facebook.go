@@ -0,0 +1,103 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("facebook", func() ProviderDriver { return &facebookDriver{} })
+}
+
+type facebookDriver struct{}
+
+func (d *facebookDriver) AuthHeader(token string) (string, string) {
+	return "", ""
+}
+
+func (d *facebookDriver) UserInfoRequest(b *IdentityProvider, token string) (*http.Request, error) {
+	userURL := "https://graph.facebook.com/me"
+
+	h := hmac.New(sha256.New, []byte(b.config.ClientSecret))
+	h.Write([]byte(token))
+	appSecretProof := hex.EncodeToString(h.Sum(nil))
+
+	params := url.Values{}
+	// See https://developers.facebook.com/docs/graph-api/reference/user/
+	params.Set("fields", "id,first_name,last_name,name,email")
+	params.Set("access_token", token)
+	params.Set("appsecret_proof", appSecretProof)
+
+	req, err := http.NewRequest("GET", userURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = params.Encode()
+	return req, nil
+}
+
+func (d *facebookDriver) ParseUserInfo(b *IdentityProvider, body []byte) (map[string]interface{}, []string, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, nil, err
+	}
+
+	if _, exists := data["error"]; exists {
+		switch errData := data["error"].(type) {
+		case map[string]interface{}:
+			var fbError strings.Builder
+			if v, exists := errData["code"]; exists {
+				errCode := strconv.FormatFloat(v.(float64), 'f', 0, 64)
+				fbError.WriteString("code=" + errCode)
+			}
+			for _, k := range []string{"fbtrace_id", "message", "type"} {
+				if v, exists := errData[k]; exists {
+					fbError.WriteString(", " + k + "=" + v.(string))
+				}
+			}
+			return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, error: %s", fbError.String())
+		default:
+			return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, error: %v", data["error"])
+		}
+	}
+	for _, k := range []string{"name", "id"} {
+		if _, exists := data[k]; !exists {
+			return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, field %s not found, data: %v", k, data)
+		}
+	}
+
+	m := make(map[string]interface{})
+	if v, exists := data["email"]; exists {
+		m["email"] = v
+	}
+	m["sub"] = data["id"]
+	m["name"] = data["name"]
+
+	return m, nil, nil
+}
+
+func (d *facebookDriver) FetchGroups(ctx context.Context, b *IdentityProvider, token string) ([]string, error) {
+	return nil, nil
+}
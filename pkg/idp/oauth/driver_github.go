@@ -0,0 +1,150 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("github", func() ProviderDriver { return &githubDriver{} })
+}
+
+type githubDriver struct{}
+
+func (d *githubDriver) AuthHeader(token string) (string, string) {
+	return "Authorization", "token " + token
+}
+
+func (d *githubDriver) UserInfoRequest(b *IdentityProvider, token string) (*http.Request, error) {
+	return http.NewRequest("GET", "https://api.github.com/user", nil)
+}
+
+func (d *githubDriver) ParseUserInfo(b *IdentityProvider, body []byte) (map[string]interface{}, []string, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, nil, err
+	}
+
+	if _, exists := data["message"]; exists {
+		return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, error: %s", data["message"].(string))
+	}
+	if _, exists := data["login"]; !exists {
+		return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, login field not found")
+	}
+
+	m := make(map[string]interface{})
+	if v, exists := data["login"]; exists {
+		switch login := v.(type) {
+		case string:
+			m["sub"] = "github.com/" + login
+		}
+	}
+	if v, exists := data["name"]; exists {
+		switch name := v.(type) {
+		case string:
+			m["name"] = name
+		}
+	}
+	if v, exists := data["avatar_url"]; exists {
+		switch avatarURL := v.(type) {
+		case string:
+			m["picture"] = avatarURL
+		}
+	}
+	metadata := make(map[string]interface{})
+	if v, exists := data["id"]; exists {
+		metadata["id"] = v
+	}
+	m["metadata"] = metadata
+
+	b.logger.Debug(
+		"Extracted UserInfo endpoint data",
+		zap.String("identity_provider_name", b.config.Name),
+		zap.Any("inputted", data),
+		zap.Any("extracted", m),
+	)
+
+	return m, nil, nil
+}
+
+func (d *githubDriver) FetchGroups(ctx context.Context, b *IdentityProvider, token string) ([]string, error) {
+	if len(b.userOrgFilters) == 0 {
+		return nil, nil
+	}
+
+	// Intentionally calls the fixed /user/orgs endpoint rather than following
+	// the user object's per-account organizations_url: organizations_url
+	// always points at the same resource for a given user, and hardcoding it
+	// avoids a dependency on ParseUserInfo having populated it first.
+	reqURL := "https://api.github.com/user/orgs"
+	cli, err := b.httpClientFor()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Authorization", "token "+token)
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := b.readLimitedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	b.logger.Debug("Additional user data received", zap.String("url", reqURL), zap.Any("body", respBody))
+
+	orgs := []map[string]interface{}{}
+	if err := json.Unmarshal(respBody, &orgs); err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	for _, org := range orgs {
+		v, exists := org["login"]
+		if !exists {
+			continue
+		}
+		orgName, ok := v.(string)
+		if !ok {
+			continue
+		}
+		included := false
+		for _, rp := range b.userOrgFilters {
+			if rp.MatchString(orgName) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			continue
+		}
+		groups = append(groups, fmt.Sprintf("github.com/%s/members", orgName))
+	}
+
+	return groups, nil
+}
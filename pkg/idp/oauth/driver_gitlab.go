@@ -0,0 +1,100 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("gitlab", func() ProviderDriver { return &gitlabDriver{} })
+}
+
+type gitlabDriver struct{}
+
+func (d *gitlabDriver) AuthHeader(token string) (string, string) {
+	return "Authorization", "Bearer " + token
+}
+
+func (d *gitlabDriver) UserInfoRequest(b *IdentityProvider, token string) (*http.Request, error) {
+	return http.NewRequest("GET", b.userInfoURL, nil)
+}
+
+func (d *gitlabDriver) ParseUserInfo(b *IdentityProvider, body []byte) (map[string]interface{}, []string, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, nil, err
+	}
+
+	if _, exists := data["profile"]; !exists {
+		return nil, nil, fmt.Errorf("failed obtaining user profile with OAuth 2.0 access token, profile field not found")
+	}
+
+	m := make(map[string]interface{})
+	for _, k := range []string{"name", "picture", "profile", "email"} {
+		v, exists := data[k]
+		if !exists {
+			continue
+		}
+		switch s := v.(type) {
+		case string:
+			switch k {
+			case "profile":
+				m["sub"] = s
+			default:
+				m[k] = s
+			}
+		}
+	}
+
+	var groups []string
+	if len(b.userGroupFilters) > 0 {
+		if v, exists := data["groups"]; exists {
+			switch rawGroups := v.(type) {
+			case []interface{}:
+				for _, g := range rawGroups {
+					groupName, ok := g.(string)
+					if !ok {
+						continue
+					}
+					for _, rp := range b.userGroupFilters {
+						if !rp.MatchString(groupName) {
+							continue
+						}
+						groups = append(groups, b.serverName+"/"+groupName)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	b.logger.Debug(
+		"Extracted UserInfo endpoint data",
+		zap.String("identity_provider_name", b.config.Name),
+		zap.Any("data", m),
+	)
+
+	return m, groups, nil
+}
+
+func (d *gitlabDriver) FetchGroups(ctx context.Context, b *IdentityProvider, token string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// defaultMaxResponseSize bounds how much of an identity provider's user-info
+// or group-enumeration response fetchClaims and the ProviderDriver
+// implementations will read into memory, absent an operator override via
+// WithMaxResponseSize.
+const defaultMaxResponseSize = 1 << 20 // 1 MiB
+
+// IdentityProviderOption configures an IdentityProvider at construction
+// time.
+type IdentityProviderOption func(*IdentityProvider) error
+
+// WithHTTPClient overrides the *http.Client the identity provider uses to
+// call the OAuth 2.0 user-info and group-enumeration endpoints, in place of
+// the client newBrowser would otherwise build. Operators use this to route
+// those calls through a proxy, present client certificates for mTLS, or, in
+// tests, to point the identity provider at a fixture server without relying
+// on URL overrides alone.
+func WithHTTPClient(cli *http.Client) IdentityProviderOption {
+	return func(b *IdentityProvider) error {
+		if cli == nil {
+			return fmt.Errorf("http client must not be nil")
+		}
+		b.httpClient = cli
+		return nil
+	}
+}
+
+// WithTransport overrides the http.RoundTripper used by the identity
+// provider's HTTP client, leaving timeouts, cookie jar, and other client
+// settings from newBrowser in place.
+func WithTransport(rt http.RoundTripper) IdentityProviderOption {
+	return func(b *IdentityProvider) error {
+		if rt == nil {
+			return fmt.Errorf("http transport must not be nil")
+		}
+		b.transport = rt
+		return nil
+	}
+}
+
+// WithMaxResponseSize overrides the default 1 MiB cap on how much of an
+// identity provider response fetchClaims and the ProviderDriver
+// implementations will read into memory.
+func WithMaxResponseSize(n int64) IdentityProviderOption {
+	return func(b *IdentityProvider) error {
+		if n <= 0 {
+			return fmt.Errorf("max response size must be positive")
+		}
+		b.maxResponseSize = n
+		return nil
+	}
+}
+
+// httpClientFor returns the *http.Client the identity provider should use
+// for user-info and group-enumeration requests: an explicitly injected
+// client or transport takes precedence over the client newBrowser builds.
+func (b *IdentityProvider) httpClientFor() (*http.Client, error) {
+	if b.httpClient != nil {
+		return b.httpClient, nil
+	}
+	cli, err := b.newBrowser()
+	if err != nil {
+		return nil, err
+	}
+	if b.transport != nil {
+		cli.Transport = b.transport
+	}
+	return cli, nil
+}
+
+// maxResponseSizeOrDefault returns the configured response-size cap, or
+// defaultMaxResponseSize if none was set.
+func (b *IdentityProvider) maxResponseSizeOrDefault() int64 {
+	if b.maxResponseSize > 0 {
+		return b.maxResponseSize
+	}
+	return defaultMaxResponseSize
+}
+
+// readLimitedBody reads resp.Body up to the identity provider's configured
+// max-response-size, returning an error if the body is larger, so a hostile
+// or misbehaving identity provider cannot OOM the process.
+func (b *IdentityProvider) readLimitedBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	limit := b.maxResponseSizeOrDefault()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("identity provider response exceeds max allowed size of %d bytes", limit)
+	}
+	return body, nil
+}
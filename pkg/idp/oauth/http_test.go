@@ -0,0 +1,109 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestFetchClaimsRejectsOversizeResponse(t *testing.T) {
+	oversizeBody := `{"sub": "jsmith", "name": "` + strings.Repeat("a", 64) + `"}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(oversizeBody))
+	}))
+	defer srv.Close()
+
+	b := &IdentityProvider{
+		logger:          zap.NewNop(),
+		userInfoURL:     srv.URL,
+		maxResponseSize: 16,
+	}
+	b.config = &Config{Driver: "casdoor", Name: "casdoor_test"}
+
+	_, err := b.fetchClaims(context.Background(), map[string]interface{}{"access_token": "test-token"})
+	if err == nil {
+		t.Fatal("expected an error for an oversize response, got nil")
+	}
+}
+
+func TestFetchClaimsHonorsContextDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sub": "jsmith"}`))
+	}))
+	defer srv.Close()
+
+	b := &IdentityProvider{
+		logger:      zap.NewNop(),
+		userInfoURL: srv.URL,
+	}
+	b.config = &Config{Driver: "casdoor", Name: "casdoor_test"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	defer cancel()
+
+	_, err := b.fetchClaims(ctx, map[string]interface{}{"access_token": "test-token"})
+	if err == nil {
+		t.Fatal("expected a context deadline error, got nil")
+	}
+}
+
+func TestWithHTTPClientInjectsTransport(t *testing.T) {
+	var used bool
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		used = true
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sub": "jsmith"}`))
+	}))
+	defer srv.Close()
+
+	b := &IdentityProvider{
+		logger:      zap.NewNop(),
+		userInfoURL: srv.URL,
+	}
+	b.config = &Config{Driver: "casdoor", Name: "casdoor_test"}
+
+	if err := WithHTTPClient(&http.Client{Transport: rt})(b); err != nil {
+		t.Fatalf("WithHTTPClient failed: %v", err)
+	}
+
+	if _, err := b.fetchClaims(context.Background(), map[string]interface{}{"access_token": "test-token"}); err != nil {
+		t.Fatalf("fetchClaims failed: %v", err)
+	}
+
+	if !used {
+		t.Fatal("expected the injected transport to be used")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"go.uber.org/zap"
+)
+
+// IdentityProvider handles OAuth 2.0 user-info and group-enumeration
+// exchanges for a single configured identity provider, dispatching to the
+// ProviderDriver registered for its Driver.
+type IdentityProvider struct {
+	config *Config
+	logger *zap.Logger
+
+	serverName  string
+	userInfoURL string
+
+	userOrgFilters   []*regexp.Regexp
+	userGroupFilters []*regexp.Regexp
+
+	scopeMap map[string]bool
+
+	policyMap *PolicyMap
+
+	httpClient      *http.Client
+	transport       http.RoundTripper
+	maxResponseSize int64
+}
+
+// NewIdentityProvider validates cfg, compiles its org/group filters, applies
+// opts, and returns an IdentityProvider ready to handle fetchClaims for
+// cfg.Driver.
+func NewIdentityProvider(cfg *Config, logger *zap.Logger, opts ...IdentityProviderOption) (*IdentityProvider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	b := &IdentityProvider{
+		config:      cfg,
+		logger:      logger,
+		serverName:  cfg.ServerName,
+		userInfoURL: cfg.UserInfoURL,
+		scopeMap:    make(map[string]bool),
+		policyMap:   cfg.PolicyMap,
+	}
+
+	for _, scope := range cfg.Scopes {
+		b.scopeMap[scope] = true
+	}
+
+	for _, pattern := range cfg.UserOrgFilters {
+		rp, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("identity provider %q: invalid user_org_filters pattern %q: %v", cfg.Name, pattern, err)
+		}
+		b.userOrgFilters = append(b.userOrgFilters, rp)
+	}
+	for _, pattern := range cfg.UserGroupFilters {
+		rp, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("identity provider %q: invalid user_group_filters pattern %q: %v", cfg.Name, pattern, err)
+		}
+		b.userGroupFilters = append(b.userGroupFilters, rp)
+	}
+
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// newBrowser builds the default *http.Client used for outbound identity
+// provider requests when no WithHTTPClient override is configured.
+func (b *IdentityProvider) newBrowser() (*http.Client, error) {
+	return &http.Client{}, nil
+}
+
+// ScopeExists reports whether scope was requested for this identity
+// provider, per its configured Scopes.
+func (b *IdentityProvider) ScopeExists(scope string) bool {
+	return b.scopeMap[scope]
+}
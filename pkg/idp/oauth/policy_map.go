@@ -0,0 +1,117 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"fmt"
+	"path"
+)
+
+// PolicyMapRule maps a single team/organization or guild/role group string,
+// e.g. "github.com/acme/admins" or "discord.com/<guildID>/role/<roleID>",
+// emitted by fetchClaims to a set of authcrunch roles. The Match field
+// supports glob patterns, e.g. "github.com/acme/*".
+type PolicyMapRule struct {
+	Match string   `json:"match,omitempty" yaml:"match,omitempty"`
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// PolicyMap translates the raw group strings an OAuth 2.0 identity provider
+// emits into authcrunch roles. It is configured per identity provider and
+// applied by fetchClaims after the provider-specific claims have been
+// extracted.
+type PolicyMap struct {
+	// Rules is the list of group-to-roles mappings. Every rule whose Match
+	// pattern matches a group contributes its roles; matches are not
+	// exclusive, so a group can pick up roles from more than one rule.
+	Rules []PolicyMapRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// DefaultRoles is assigned when no rule matches any of the user's groups.
+	DefaultRoles []string `json:"default_roles,omitempty" yaml:"default_roles,omitempty"`
+	// UserRoles overrides the computed roles for specific subjects, keyed by
+	// the exact value of the sub claim, regardless of what the rules above
+	// would have produced.
+	UserRoles map[string][]string `json:"user_roles,omitempty" yaml:"user_roles,omitempty"`
+}
+
+// Validate checks the PolicyMap for configuration errors, e.g. malformed
+// glob patterns or rules without any roles.
+func (m *PolicyMap) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for _, rule := range m.Rules {
+		if rule.Match == "" {
+			return fmt.Errorf("policy map rule has no match pattern")
+		}
+		if len(rule.Roles) == 0 {
+			return fmt.Errorf("policy map rule %q has no roles", rule.Match)
+		}
+		if _, err := path.Match(rule.Match, "probe"); err != nil {
+			return fmt.Errorf("policy map rule %q has invalid match pattern: %v", rule.Match, err)
+		}
+	}
+	return nil
+}
+
+// Roles returns the authcrunch roles associated with the given subject and
+// its groups. A user-specific override always wins. Otherwise, each group is
+// matched, in rule order, against the configured glob patterns, and the
+// roles of every matching rule are merged. When nothing matches, DefaultRoles
+// is returned.
+func (m *PolicyMap) Roles(subject string, groups []string) []string {
+	if m == nil {
+		return nil
+	}
+	if roles, exists := m.UserRoles[subject]; exists {
+		return roles
+	}
+
+	seen := make(map[string]bool)
+	var roles []string
+	for _, group := range groups {
+		for _, rule := range m.Rules {
+			matched, err := path.Match(rule.Match, group)
+			if err != nil || !matched {
+				continue
+			}
+			for _, role := range rule.Roles {
+				if seen[role] {
+					continue
+				}
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	if len(roles) == 0 {
+		return m.DefaultRoles
+	}
+	return roles
+}
+
+// applyPolicyMap resolves the configured policy map, if any, against the
+// extracted subject and groups, and attaches the resulting roles claim to
+// claims.
+func (b *IdentityProvider) applyPolicyMap(claims map[string]interface{}, groups []string) {
+	if b.policyMap == nil {
+		return
+	}
+	subject, _ := claims["sub"].(string)
+	roles := b.policyMap.Roles(subject, groups)
+	if len(roles) > 0 {
+		claims["roles"] = roles
+	}
+}
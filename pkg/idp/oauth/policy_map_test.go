@@ -0,0 +1,115 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+)
+
+func TestPolicyMapValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		m         *PolicyMap
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "valid rules",
+			m: &PolicyMap{
+				Rules: []PolicyMapRule{
+					{Match: "github.com/acme/*", Roles: []string{"admin"}},
+				},
+			},
+		},
+		{
+			name: "rule without roles",
+			m: &PolicyMap{
+				Rules: []PolicyMapRule{
+					{Match: "github.com/acme/*"},
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf(`policy map rule "github.com/acme/*" has no roles`),
+		},
+		{
+			name: "rule without match pattern",
+			m: &PolicyMap{
+				Rules: []PolicyMapRule{
+					{Roles: []string{"admin"}},
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("policy map rule has no match pattern"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.m.Validate()
+			tests.EvalErrWithLog(t, err, "Validate", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestPolicyMapRoles(t *testing.T) {
+	m := &PolicyMap{
+		Rules: []PolicyMapRule{
+			{Match: "github.com/acme/admins", Roles: []string{"admin"}},
+			{Match: "github.com/acme/*", Roles: []string{"viewer"}},
+		},
+		DefaultRoles: []string{"guest"},
+		UserRoles: map[string][]string{
+			"github.com/root": {"superadmin"},
+		},
+	}
+
+	testcases := []struct {
+		name    string
+		subject string
+		groups  []string
+		want    []string
+	}{
+		{
+			name:    "user override wins",
+			subject: "github.com/root",
+			groups:  []string{"github.com/acme/members"},
+			want:    []string{"superadmin"},
+		},
+		{
+			name:    "matches both the exact and glob rule",
+			subject: "github.com/jane",
+			groups:  []string{"github.com/acme/admins"},
+			want:    []string{"admin", "viewer"},
+		},
+		{
+			name:    "no matching group falls back to default",
+			subject: "github.com/jane",
+			groups:  []string{"github.com/other/members"},
+			want:    []string{"guest"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := m.Roles(tc.subject, tc.groups)
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			tests.EvalObjectsWithLog(t, "roles", tc.want, got, msgs)
+		})
+	}
+}
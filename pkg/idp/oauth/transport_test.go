@@ -0,0 +1,35 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// hostRedirectTransport redirects every request to base's scheme and host,
+// preserving path and query, so drivers with a hardcoded production API
+// host can be exercised against a local httptest server.
+type hostRedirectTransport struct {
+	base *url.URL
+}
+
+func (rt hostRedirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	redirected := r.Clone(r.Context())
+	redirected.URL.Scheme = rt.base.Scheme
+	redirected.URL.Host = rt.base.Host
+	redirected.Host = rt.base.Host
+	return http.DefaultTransport.RoundTrip(redirected)
+}
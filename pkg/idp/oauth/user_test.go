@@ -0,0 +1,74 @@
+// Copyright 2024 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"go.uber.org/zap"
+)
+
+func TestFetchClaimsUnsupportedDriver(t *testing.T) {
+	b := &IdentityProvider{logger: zap.NewNop()}
+	b.config = &Config{Driver: "not-a-real-driver", Name: "unsupported_test"}
+
+	_, err := b.fetchClaims(context.Background(), map[string]interface{}{"access_token": "test-token"})
+	msgs := []string{"test name: unsupported driver"}
+	wantErr := fmt.Errorf("provider not-a-real-driver is unsupported for fetching claims")
+	tests.EvalErrWithLog(t, err, "fetchClaims", true, wantErr, msgs)
+}
+
+func TestFetchClaimsAppliesPolicyMap(t *testing.T) {
+	respBody := `{
+		"kind": "User",
+		"metadata": {"name": "jsmith"},
+		"fullName": "John Smith",
+		"groups": ["cluster-admins"]
+	}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+
+	b := &IdentityProvider{
+		logger:           zap.NewNop(),
+		serverName:       "openshift.example.com",
+		userInfoURL:      srv.URL,
+		userGroupFilters: []*regexp.Regexp{regexp.MustCompile("cluster-admins")},
+		policyMap: &PolicyMap{
+			Rules: []PolicyMapRule{
+				{Match: "openshift.example.com/cluster-admins", Roles: []string{"admin"}},
+			},
+			DefaultRoles: []string{"guest"},
+		},
+	}
+	b.config = &Config{Driver: "openshift", Name: "openshift_test"}
+
+	got, err := b.fetchClaims(context.Background(), map[string]interface{}{"access_token": "test-token"})
+	if err != nil {
+		t.Fatalf("fetchClaims failed: %v", err)
+	}
+
+	msgs := []string{"test name: policy map wiring"}
+	tests.EvalObjectsWithLog(t, "roles", []string{"admin"}, got["roles"], msgs)
+}